@@ -0,0 +1,50 @@
+package csimanager
+
+import "time"
+
+// EventType enumerates the lifecycle transitions volumeManager reports, so
+// operators can debug stuck allocations from the Nomad event stream instead
+// of tailing client logs.
+type EventType string
+
+const (
+	EventTypeMountSucceeded   EventType = "mount_succeeded"
+	EventTypeMountRetried     EventType = "mount_retried"
+	EventTypeMountCorrupted   EventType = "mount_corrupted"
+	EventTypeUnmountSucceeded EventType = "unmount_succeeded"
+)
+
+// Event is a structured record of a single CSI node RPC lifecycle
+// transition for a given volume.
+type Event struct {
+	Type      EventType
+	PluginID  string
+	VolumeID  string
+	AllocID   string
+	Message   string
+	Timestamp time.Time
+}
+
+// Events returns a channel of volumeManager lifecycle events. Consumers
+// (e.g. the client's event stream publisher) are expected to drain it
+// promptly; emitEvent never blocks producing callers waiting on one.
+func (v *volumeManager) Events() <-chan *Event {
+	return v.events
+}
+
+// emitEvent delivers ev to the events channel without blocking, and always
+// logs it first. A volumeManager with no active subscriber (e.g. in tests,
+// between publisher restarts, or simply because nothing has wired Events()
+// up yet) would otherwise drop the event with no trace of it anywhere;
+// logging ensures it's still visible to an operator tailing client logs.
+func (v *volumeManager) emitEvent(ev *Event) {
+	ev.PluginID = v.pluginID
+	ev.Timestamp = time.Now()
+
+	v.logger.Debug("volume event", "type", ev.Type, "volume_id", ev.VolumeID, "alloc_id", ev.AllocID, "message", ev.Message)
+
+	select {
+	case v.events <- ev:
+	default:
+	}
+}
@@ -0,0 +1,117 @@
+package csimanager
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const journalStateDirName = "state"
+
+// volumeJournalEntry captures everything a volumeManager needs to resume
+// managing a (volume, usage) tuple across a Nomad client restart: where it
+// is staged, the usage mode it's staged for, the publish context the
+// controller returned, and which allocations are currently publishing
+// from it.
+type volumeJournalEntry struct {
+	VolumeID       string            `json:"volume_id"`
+	StagingPath    string            `json:"staging_path"`
+	Usage          *UsageOptions     `json:"usage"`
+	PublishContext map[string]string `json:"publish_context"`
+	AllocIDs       []string          `json:"alloc_ids"`
+}
+
+func (e *volumeJournalEntry) removeAlloc(allocID string) {
+	for i, id := range e.AllocIDs {
+		if id == allocID {
+			e.AllocIDs = append(e.AllocIDs[:i], e.AllocIDs[i+1:]...)
+			return
+		}
+	}
+}
+
+// volumeJournal persists in-flight volume usage to a JSON file under the
+// plugin's mount root, so that newVolumeManager can tell, after a client
+// restart, which volumes are already staged/published rather than
+// re-staging (potentially failing against a plugin that forbids concurrent
+// stages) or leaking the mount entirely.
+type volumeJournal struct {
+	mu   sync.Mutex
+	path string
+}
+
+func newVolumeJournal(mountRoot string) *volumeJournal {
+	return &volumeJournal{
+		path: filepath.Join(mountRoot, journalStateDirName, "volumes.json"),
+	}
+}
+
+// load reads the on-disk journal, keyed by the same (volumeID, usage)
+// unique key used by volumeUsageTracker. A missing file is not an error: it
+// means this is the first time the manager has run against this mount
+// root.
+func (j *volumeJournal) load() (map[string]*volumeJournalEntry, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	entries := make(map[string]*volumeJournalEntry)
+
+	raw, err := ioutil.ReadFile(j.path)
+	if os.IsNotExist(err) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read volume journal: %v", err)
+	}
+	if len(raw) == 0 {
+		return entries, nil
+	}
+
+	if err := json.Unmarshal(raw, &entries); err != nil {
+		return nil, fmt.Errorf("failed to decode volume journal: %v", err)
+	}
+
+	return entries, nil
+}
+
+// persist writes the given journal state to disk and fsyncs it before
+// returning, so that MountVolume/UnmountVolume callers can rely on the
+// journal being durable once they observe a nil error. It writes to a temp
+// file and renames over the journal path so a crash mid-write can never
+// leave a partially-written journal behind.
+func (j *volumeJournal) persist(entries map[string]*volumeJournalEntry) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(j.path), 0700); err != nil {
+		return fmt.Errorf("failed to create volume journal directory: %v", err)
+	}
+
+	raw, err := json.Marshal(entries)
+	if err != nil {
+		return fmt.Errorf("failed to encode volume journal: %v", err)
+	}
+
+	tmpPath := j.path + ".tmp"
+	f, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open volume journal: %v", err)
+	}
+
+	if _, err := f.Write(raw); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write volume journal: %v", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to fsync volume journal: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to close volume journal: %v", err)
+	}
+
+	return os.Rename(tmpPath, j.path)
+}
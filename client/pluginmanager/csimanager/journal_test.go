@@ -0,0 +1,78 @@
+package csimanager
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// TestVolumeJournal_PersistLoadRoundTrip exercises the durability path that
+// restoreFromJournal's dead-alloc reconciliation depends on: entries (and
+// the AllocIDs mutations reconciliation makes to them) must survive a
+// persist/load cycle exactly, including an entry left with an empty
+// AllocIDs list pending an unstage retry.
+func TestVolumeJournal_PersistLoadRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	j := newVolumeJournal(dir)
+
+	entries := map[string]*volumeJournalEntry{
+		"vol-1/rw-file-system-single-node-writer": {
+			VolumeID:       "vol-1",
+			StagingPath:    filepath.Join(dir, "staging", "vol-1"),
+			Usage:          &UsageOptions{AttachmentMode: "file-system", AccessMode: "single-node-writer"},
+			PublishContext: map[string]string{"device": "/dev/fake0"},
+			AllocIDs:       []string{"alloc-1", "alloc-2"},
+		},
+		"vol-2/rw-file-system-single-node-writer": {
+			VolumeID:    "vol-2",
+			StagingPath: filepath.Join(dir, "staging", "vol-2"),
+			AllocIDs:    []string{},
+		},
+	}
+
+	if err := j.persist(entries); err != nil {
+		t.Fatalf("persist failed: %v", err)
+	}
+
+	loaded, err := j.load()
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+
+	if len(loaded) != len(entries) {
+		t.Fatalf("expected %d entries, got %d", len(entries), len(loaded))
+	}
+
+	got, ok := loaded["vol-1/rw-file-system-single-node-writer"]
+	if !ok {
+		t.Fatalf("expected entry for vol-1 to survive the round trip")
+	}
+	if len(got.AllocIDs) != 2 || got.AllocIDs[0] != "alloc-1" || got.AllocIDs[1] != "alloc-2" {
+		t.Fatalf("expected AllocIDs [alloc-1 alloc-2], got %v", got.AllocIDs)
+	}
+
+	pending, ok := loaded["vol-2/rw-file-system-single-node-writer"]
+	if !ok {
+		t.Fatalf("expected entry for vol-2 to survive the round trip")
+	}
+	if len(pending.AllocIDs) != 0 {
+		t.Fatalf("expected vol-2 to be a pending-unstage entry with no allocs, got %v", pending.AllocIDs)
+	}
+}
+
+// TestVolumeJournalEntry_RemoveAlloc ensures removeAlloc drops exactly the
+// requested alloc ID and leaves the rest of the list untouched, including
+// when it is the last remaining alloc (the case that leaves an entry
+// pending an unstage retry).
+func TestVolumeJournalEntry_RemoveAlloc(t *testing.T) {
+	entry := &volumeJournalEntry{AllocIDs: []string{"alloc-1", "alloc-2"}}
+
+	entry.removeAlloc("alloc-1")
+	if len(entry.AllocIDs) != 1 || entry.AllocIDs[0] != "alloc-2" {
+		t.Fatalf("expected [alloc-2] remaining, got %v", entry.AllocIDs)
+	}
+
+	entry.removeAlloc("alloc-2")
+	if len(entry.AllocIDs) != 0 {
+		t.Fatalf("expected no allocs remaining, got %v", entry.AllocIDs)
+	}
+}
@@ -0,0 +1,43 @@
+package csimanager
+
+import (
+	"time"
+
+	metrics "github.com/armon/go-metrics"
+	"google.golang.org/grpc/status"
+)
+
+// recordRPCMetrics emits a latency histogram and a success/error counter,
+// labeled by plugin ID and (on error) gRPC status code, for a single CSI
+// node RPC. Together with the EventTypeMountRetried event, this is how the
+// client surfaces the retry/backoff behavior that grpc_retry otherwise
+// swallows silently.
+func (v *volumeManager) recordRPCMetrics(rpc string, start time.Time, err error) {
+	labels := []metrics.Label{{Name: "plugin_id", Value: v.pluginID}}
+
+	metrics.MeasureSinceWithLabels([]string{"client", "csi", rpc}, start, labels)
+
+	if err != nil {
+		errLabels := append(labels, metrics.Label{Name: "error", Value: status.Code(err).String()})
+		metrics.IncrCounterWithLabels([]string{"client", "csi", rpc, "error"}, 1, errLabels)
+		return
+	}
+
+	metrics.IncrCounterWithLabels([]string{"client", "csi", rpc, "success"}, 1, labels)
+}
+
+// updateVolumeGauges publishes the current count of staged (volume, usage)
+// tuples, and of allocations publishing them summed across all tuples, for
+// this plugin so operators can see at a glance
+// how many mounts a plugin is carrying without counting log lines.
+func (v *volumeManager) updateVolumeGauges() {
+	labels := []metrics.Label{{Name: "plugin_id", Value: v.pluginID}}
+
+	published := 0
+	for _, entry := range v.volumes {
+		published += len(entry.AllocIDs)
+	}
+
+	metrics.SetGaugeWithLabels([]string{"client", "csi", "staged_volumes"}, float32(len(v.volumes)), labels)
+	metrics.SetGaugeWithLabels([]string{"client", "csi", "published_volumes"}, float32(published), labels)
+}
@@ -0,0 +1,80 @@
+package csimanager
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// volumeUsageTracker tracks the allocations that are currently using a given
+// (volume, usage) tuple so that volumeManager knows when it is safe to tear
+// down a staging mount. Multiple allocations on the same node may share a
+// single staging mount (e.g. a multi-node-multi-writer filesystem volume),
+// so the staging mount and NodeUnstageVolume must only fire once the last
+// allocation using the tuple has released it.
+type volumeUsageTracker struct {
+	stateMu sync.Mutex
+	state   map[string][]string
+}
+
+func newVolumeUsageTracker() *volumeUsageTracker {
+	return &volumeUsageTracker{
+		state: make(map[string][]string),
+	}
+}
+
+// uniqueKey returns a key that uniquely identifies a (volume, usage) tuple,
+// for use as a key into the tracker's internal state.
+func (v *volumeUsageTracker) uniqueKey(vol *structs.CSIVolume, usage *UsageOptions) string {
+	return fmt.Sprintf("%s/%s", vol.ID, usage.ToFS())
+}
+
+func (v *volumeUsageTracker) allocsForKey(key string) []string {
+	return v.state[key]
+}
+
+// appendAlloc adds allocID to key's list, unless it is already present. A
+// duplicate append here would mean Free never sees the list reach zero, so
+// NodeUnstageVolume never fires and the staging mount leaks permanently —
+// this can happen in practice when a hook retries or replays a MountVolume
+// call for an alloc it has already claimed.
+func (v *volumeUsageTracker) appendAlloc(key, allocID string) {
+	for _, id := range v.allocsForKey(key) {
+		if id == allocID {
+			return
+		}
+	}
+	v.state[key] = append(v.allocsForKey(key), allocID)
+}
+
+func (v *volumeUsageTracker) removeAlloc(key, allocID string) {
+	allocs := v.allocsForKey(key)
+	for i, id := range allocs {
+		if id == allocID {
+			v.state[key] = append(allocs[:i], allocs[i+1:]...)
+			return
+		}
+	}
+}
+
+// Claim records that the given allocation is now using the (volume, usage)
+// tuple identified by key.
+func (v *volumeUsageTracker) Claim(allocID, key string) {
+	v.stateMu.Lock()
+	defer v.stateMu.Unlock()
+
+	v.appendAlloc(key, allocID)
+}
+
+// Free removes the given allocation from the usage tracker and reports
+// whether no allocations remain using the (volume, usage) tuple. When it
+// returns true, the caller was the last user and should tear down the
+// staging mount.
+func (v *volumeUsageTracker) Free(allocID, key string) bool {
+	v.stateMu.Lock()
+	defer v.stateMu.Unlock()
+
+	v.removeAlloc(key, allocID)
+	return len(v.allocsForKey(key)) == 0
+}
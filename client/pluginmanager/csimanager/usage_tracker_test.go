@@ -0,0 +1,60 @@
+package csimanager
+
+import "testing"
+
+// TestVolumeUsageTracker_ClaimDedup reproduces the refcount leak where a
+// repeated Claim for an allocation already tracked under a key (e.g. a hook
+// retry replaying MountVolume) would append a duplicate alloc ID, so a
+// single Free call would never bring the list back to zero and the staging
+// mount would never be torn down.
+func TestVolumeUsageTracker_ClaimDedup(t *testing.T) {
+	cases := []struct {
+		name   string
+		claims []string
+		frees  []string
+		expect bool
+	}{
+		{
+			name:   "single claim single free",
+			claims: []string{"alloc-1"},
+			frees:  []string{"alloc-1"},
+			expect: true,
+		},
+		{
+			name:   "duplicate claim single free",
+			claims: []string{"alloc-1", "alloc-1"},
+			frees:  []string{"alloc-1"},
+			expect: true,
+		},
+		{
+			name:   "two distinct allocs require two frees",
+			claims: []string{"alloc-1", "alloc-2"},
+			frees:  []string{"alloc-1"},
+			expect: false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tracker := newVolumeUsageTracker()
+			key := "vol-1/rw-file-system-single-node-writer"
+
+			for _, allocID := range tc.claims {
+				tracker.Claim(allocID, key)
+			}
+
+			var lastUser bool
+			for _, allocID := range tc.frees {
+				lastUser = tracker.Free(allocID, key)
+			}
+
+			if lastUser != tc.expect {
+				t.Fatalf("expected Free to report lastUser=%v, got %v", tc.expect, lastUser)
+			}
+
+			if got := len(tracker.allocsForKey(key)); tc.expect && got != 0 {
+				t.Fatalf("expected no remaining allocs for key, found %d", got)
+			}
+		})
+	}
+}
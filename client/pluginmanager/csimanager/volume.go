@@ -5,6 +5,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	grpc_retry "github.com/grpc-ecosystem/go-grpc-middleware/retry"
@@ -12,6 +15,8 @@ import (
 	"github.com/hashicorp/nomad/helper/mount"
 	"github.com/hashicorp/nomad/nomad/structs"
 	"github.com/hashicorp/nomad/plugins/csi"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 var _ VolumeMounter = &volumeManager{}
@@ -22,6 +27,89 @@ const (
 	AllocSpecificDirName      = "per-alloc"
 )
 
+// CSIMountOptions captures the operator-specified mount options for a
+// filesystem volume, e.g. `nfsvers=4.1` or `noatime`, as set on the volume
+// registration or overridden in the jobspec `volume` stanza.
+type CSIMountOptions struct {
+	FSType     string
+	MountFlags []string
+}
+
+// merge returns a copy of o with any non-zero fields of other applied over
+// it, matching the precedence jobspec-level overrides take over the
+// volume's registered defaults.
+func (o *CSIMountOptions) merge(other *CSIMountOptions) *CSIMountOptions {
+	if o == nil {
+		return other
+	}
+	if other == nil {
+		return o
+	}
+
+	result := *o
+	if other.FSType != "" {
+		result.FSType = other.FSType
+	}
+	if len(other.MountFlags) != 0 {
+		result.MountFlags = other.MountFlags
+	}
+	return &result
+}
+
+// volumeRegistrationMountOptions converts the mount_options recorded on the
+// volume at registration time (e.g. `nfsvers=4.1`, `noatime`, set via the
+// volume registration and surfaced here so the jobspec volume stanza's
+// per-claim usage.MountOptions can override them) into a CSIMountOptions,
+// or nil if the volume was registered without any.
+func volumeRegistrationMountOptions(vol *structs.CSIVolume) *CSIMountOptions {
+	if vol.MountOptions == nil {
+		return nil
+	}
+	return &CSIMountOptions{
+		FSType:     vol.MountOptions.FSType,
+		MountFlags: vol.MountOptions.MountFlags,
+	}
+}
+
+// UsageOptions contains the extra set of options to provide to NodeStage,
+// NodePublish, and derived internal methods in order to capture the
+// differing usage modes a volume may be used with in a given allocation,
+// e.g. the attachment/access mode and whether it is mounted read-only.
+type UsageOptions struct {
+	ReadOnly       bool
+	AttachmentMode structs.CSIVolumeAttachmentMode
+	AccessMode     structs.CSIVolumeAccessMode
+	MountOptions   *CSIMountOptions
+
+	// FSGroup is the group ownership the mounted filesystem should carry so
+	// that the allocation's tasks (which may run as a different uid/gid
+	// than the CSI plugin) can read and write it. Nil means the task/volume
+	// mount spec did not request one.
+	FSGroup *int64
+
+	// SELinuxLabel is the MCS category the allocation's tasks are running
+	// under, e.g. "system_u:object_r:container_file_t:s0:c1,c2". Empty
+	// means no relabeling was requested.
+	SELinuxLabel string
+}
+
+// ToFS is used to uniquely identify the usage options, for use in a file
+// path, e.g when creating the staging directory for a given usage mode.
+func (u *UsageOptions) ToFS() string {
+	var sb strings.Builder
+	if u.ReadOnly {
+		sb.WriteString("ro-")
+	} else {
+		sb.WriteString("rw-")
+	}
+
+	sb.WriteString(string(u.AttachmentMode))
+	sb.WriteString("-")
+	sb.WriteString(string(u.AccessMode))
+
+	return sb.String()
+}
+
 // volumeManager handles the state of attached volumes for a given CSI Plugin.
 //
 // volumeManagers outlive the lifetime of a given allocation as volumes may be
@@ -33,8 +121,22 @@ type volumeManager struct {
 	logger hclog.Logger
 	plugin csi.CSIPlugin
 
-	volumes map[string]interface{}
-	// volumesMu sync.Mutex
+	// pluginID labels the metrics and events this volumeManager emits, so
+	// operators can tell which plugin a given mount/event belongs to.
+	pluginID string
+
+	// events carries lifecycle transitions for consumption by the client's
+	// event stream publisher.
+	events chan *Event
+
+	usageTracker *volumeUsageTracker
+
+	// journal persists volumes to an on-disk state journal so that
+	// newVolumeManager can reconcile mounts left over from a previous run
+	// of the Nomad client, surviving agent restarts.
+	journal   *volumeJournal
+	volumes   map[string]*volumeJournalEntry
+	volumesMu sync.Mutex
 
 	// mountRoot is the root of where plugin directories and mounts may be created
 	// e.g /opt/nomad.d/statedir/csi/my-csi-plugin/
@@ -43,65 +145,161 @@ type volumeManager struct {
 	// requiresStaging shows whether the plugin requires that the volume manager
 	// calls NodeStageVolume and NodeUnstageVolume RPCs during setup and teardown
 	requiresStaging bool
+
+	// supportsMountGroup indicates the plugin advertises the
+	// VOLUME_MOUNT_GROUP node capability, meaning it applies the requested
+	// fsGroup itself and volumeManager should not chown the mount.
+	supportsMountGroup bool
 }
 
-func newVolumeManager(logger hclog.Logger, plugin csi.CSIPlugin, rootDir string, requiresStaging bool) *volumeManager {
-	return &volumeManager{
-		logger:          logger.Named("volume_manager"),
-		plugin:          plugin,
-		mountRoot:       rootDir,
-		requiresStaging: requiresStaging,
-		volumes:         make(map[string]interface{}),
+func newVolumeManager(logger hclog.Logger, pluginID string, plugin csi.CSIPlugin, rootDir string, requiresStaging, supportsMountGroup bool, liveAllocIDs map[string]struct{}) *volumeManager {
+	v := &volumeManager{
+		logger:             logger.Named("volume_manager"),
+		plugin:             plugin,
+		pluginID:           pluginID,
+		events:             make(chan *Event, 64),
+		mountRoot:          rootDir,
+		requiresStaging:    requiresStaging,
+		supportsMountGroup: supportsMountGroup,
+		journal:            newVolumeJournal(rootDir),
+		volumes:            make(map[string]*volumeJournalEntry),
+		usageTracker:       newVolumeUsageTracker(),
+	}
+
+	if err := v.restoreFromJournal(liveAllocIDs); err != nil {
+		v.logger.Error("failed to restore volume state from journal", "error", err)
 	}
-}
 
-func (v *volumeManager) stagingDirForVolume(vol *structs.CSIVolume) string {
-	return filepath.Join(v.mountRoot, StagingDirName, vol.ID, "todo-provide-usage-options")
+	return v
 }
 
-// ensureStagingDir attempts to create a directory for use when staging a volume
-// and then validates that the path is not already a mount point for e.g an
-// existing volume stage.
+// restoreFromJournal replays the on-disk state journal, verifying that each
+// recorded staging mount still exists and dropping (and persisting the
+// removal of) entries whose mounts were cleared out from under us, e.g. by
+// a reboot that did not cleanly unmount them.
 //
-// Returns whether the directory is a pre-existing mountpoint, the staging path,
-// and any errors that occurred.
-func (v *volumeManager) ensureStagingDir(vol *structs.CSIVolume) (bool, string, error) {
-	stagingPath := v.stagingDirForVolume(vol)
-
-	// Make the staging path, owned by the Nomad User
-	if err := os.MkdirAll(stagingPath, 0700); err != nil && !os.IsExist(err) {
-		return false, "", fmt.Errorf("failed to create staging directory for volume (%s): %v", vol.ID, err)
+// It also reconciles each surviving entry's AllocIDs against liveAllocIDs,
+// the set of allocations the client still knows about. An alloc ID that
+// isn't in that set was GC'd or terminated before its UnmountVolume call
+// ran (e.g. the client crashed mid-teardown), and would otherwise be
+// claimed forever, so it is unpublished/released here instead. Entries left
+// with no live allocations are driven all the way through unstageAndForget
+// so a crash during that process is retried on the next restart; for a
+// plugin that does not require staging they are simply forgotten.
+func (v *volumeManager) restoreFromJournal(liveAllocIDs map[string]struct{}) error {
+	entries, err := v.journal.load()
+	if err != nil {
+		return err
 	}
 
-	// Validate that it is not already a mount point
 	m := mount.New()
-	isNotMount, err := m.IsNotAMountPoint(stagingPath)
-	if err != nil {
-		return false, "", fmt.Errorf("mount point detection failed for volume (%s): %v", vol.ID, err)
+	changed := false
+	for key, entry := range entries {
+		// entry.StagingPath is only ever created/mounted when this plugin
+		// requires staging (stageVolume/ensureStagingDir are skipped
+		// entirely otherwise, even though journalClaim still records a
+		// StagingPath unconditionally). For a non-staging plugin the path
+		// was never a mountpoint, so checking it here would drop every
+		// entry as "stale" before the alloc-reconciliation loop below ever
+		// runs.
+		if v.requiresStaging {
+			isNotMount, err := m.IsNotAMountPoint(entry.StagingPath)
+			if err != nil || isNotMount {
+				v.logger.Debug("dropping stale journal entry for cleared mount", "volume_id", entry.VolumeID, "staging_path", entry.StagingPath)
+				delete(entries, key)
+				changed = true
+				continue
+			}
+		}
+
+		live := entry.AllocIDs[:0]
+		for _, allocID := range entry.AllocIDs {
+			if _, ok := liveAllocIDs[allocID]; !ok {
+				v.logger.Info("releasing volume claim for gone allocation", "volume_id", entry.VolumeID, "alloc_id", allocID)
+				if err := v.unpublishDeadAlloc(entry.VolumeID, allocID); err != nil {
+					v.logger.Error("failed to release volume claim for gone allocation", "volume_id", entry.VolumeID, "alloc_id", allocID, "error", err)
+					live = append(live, allocID)
+				} else {
+					changed = true
+				}
+				continue
+			}
+			live = append(live, allocID)
+			v.usageTracker.Claim(allocID, key)
+		}
+		entry.AllocIDs = live
+	}
+
+	v.volumesMu.Lock()
+	v.volumes = entries
+	v.volumesMu.Unlock()
+
+	for key, entry := range entries {
+		if len(entry.AllocIDs) != 0 {
+			continue
+		}
+
+		if !v.requiresStaging {
+			// No staging mount was ever created for this plugin, so there
+			// is nothing to unstage - just drop the now-empty entry.
+			if err := v.journalForgetAfterUnstage(key); err != nil {
+				v.logger.Error("failed to forget orphaned volume entry on restore", "volume_id", entry.VolumeID, "error", err)
+			}
+			continue
+		}
+
+		if err := v.unstageAndForget(key, entry); err != nil {
+			v.logger.Error("failed to unstage orphaned volume on restore", "volume_id", entry.VolumeID, "error", err)
+		} else {
+			changed = true
+		}
 	}
 
-	return !isNotMount, stagingPath, nil
+	if changed {
+		v.volumesMu.Lock()
+		defer v.volumesMu.Unlock()
+		return v.journal.persist(v.volumes)
+	}
+	return nil
 }
 
-// stageVolume prepares a volume for use by allocations. When a plugin exposes
-// the STAGE_UNSTAGE_VOLUME capability it MUST be called once-per-volume for a
-// given usage mode before the volume can be NodePublish-ed.
-func (v *volumeManager) stageVolume(ctx context.Context, vol *structs.CSIVolume) error {
-	logger := hclog.FromContext(ctx)
-	logger.Trace("Preparing volume staging environment")
-	existingMount, stagingPath, err := v.ensureStagingDir(vol)
+// unpublishDeadAlloc calls NodeUnpublishVolume for an allocation that is no
+// longer known to the client, using only the alloc's former target path (we
+// no longer have its full *structs.Allocation to work with, just the ID the
+// journal recorded).
+func (v *volumeManager) unpublishDeadAlloc(volumeID, allocID string) error {
+	targetPath := filepath.Join(v.mountRoot, AllocSpecificDirName, allocID, volumeID)
+
+	start := time.Now()
+	err := v.plugin.NodeUnpublishVolume(context.Background(), volumeID, targetPath,
+		grpc_retry.WithPerRetryTimeout(DefaultMountActionTimeout),
+		grpc_retry.WithMax(3),
+		grpc_retry.WithBackoff(grpc_retry.BackoffExponential(100*time.Millisecond)),
+	)
+	v.recordRPCMetrics("node_unpublish_volume", start, err)
 	if err != nil {
 		return err
 	}
-	logger.Trace("Volume staging environment", "pre-existing_mount", existingMount, "staging_path", stagingPath)
 
-	if existingMount {
-		logger.Debug("re-using existing staging mount for volume", "staging_path", stagingPath)
-		return nil
-	}
+	return os.RemoveAll(targetPath)
+}
 
+func (v *volumeManager) stagingDirForVolume(vol *structs.CSIVolume, usage *UsageOptions) string {
+	return filepath.Join(v.mountRoot, StagingDirName, vol.ID, usage.ToFS())
+}
+
+// allocDirForVolume returns the per-allocation directory that a volume
+// should be published into. This directory is bind-mounted (for filesystem
+// volumes) or bind-mounted as a device file (for block volumes) from either
+// the staging path or, when the plugin does not require staging, directly
+// from the plugin.
+func (v *volumeManager) allocDirForVolume(vol *structs.CSIVolume, alloc *structs.Allocation) string {
+	return filepath.Join(v.mountRoot, AllocSpecificDirName, alloc.ID, vol.ID)
+}
+
+func (v *volumeManager) volumeCapability(vol *structs.CSIVolume, usage *UsageOptions) (*csi.VolumeCapability, error) {
 	var accessType csi.VolumeAccessType
-	switch vol.AttachmentMode {
+	switch usage.AttachmentMode {
 	case structs.CSIVolumeAttachmentModeBlockDevice:
 		accessType = csi.VolumeAccessTypeBlock
 	case structs.CSIVolumeAttachmentModeFilesystem:
@@ -111,11 +309,11 @@ func (v *volumeManager) stageVolume(ctx context.Context, vol *structs.CSIVolume)
 		// final check during transformation into the requisite CSI Data type to
 		// defend against development bugs and corrupted state - and incompatible
 		// nomad versions in the future.
-		return fmt.Errorf("Unknown volume attachment mode: %s", vol.AttachmentMode)
+		return nil, fmt.Errorf("Unknown volume attachment mode: %s", usage.AttachmentMode)
 	}
 
 	var accessMode csi.VolumeAccessMode
-	switch vol.AccessMode {
+	switch usage.AccessMode {
 	case structs.CSIVolumeAccessModeSingleNodeReader:
 		accessMode = csi.VolumeAccessModeSingleNodeReaderOnly
 	case structs.CSIVolumeAccessModeSingleNodeWriter:
@@ -131,7 +329,103 @@ func (v *volumeManager) stageVolume(ctx context.Context, vol *structs.CSIVolume)
 		// final check during transformation into the requisite CSI Data type to
 		// defend against development bugs and corrupted state - and incompatible
 		// nomad versions in the future.
-		return fmt.Errorf("Unknown volume access mode: %v", vol.AccessMode)
+		return nil, fmt.Errorf("Unknown volume access mode: %v", usage.AccessMode)
+	}
+
+	if usage.ReadOnly {
+		accessMode = csi.VolumeAccessModeSingleNodeReaderOnly
+	}
+
+	mountOptions := &csi.VolumeMountOptions{}
+	if usage.AttachmentMode == structs.CSIVolumeAttachmentModeFilesystem {
+		// The jobspec volume stanza's mount_options, if set, take precedence
+		// over the defaults set on the volume at registration time.
+		if merged := volumeRegistrationMountOptions(vol).merge(usage.MountOptions); merged != nil {
+			mountOptions.FSType = merged.FSType
+			mountOptions.MountFlags = merged.MountFlags
+		}
+
+		if usage.SELinuxLabel != "" && mount.SELinuxEnabled() {
+			mountOptions.MountFlags = append(mountOptions.MountFlags, fmt.Sprintf("context=%q", usage.SELinuxLabel))
+		}
+	}
+
+	// When the plugin advertises VOLUME_MOUNT_GROUP, it applies fsGroup
+	// itself during NodePublish (see applyFSGroup, which skips Nomad's own
+	// chown in that case) - so the requested group must be forwarded here,
+	// or the plugin has no way to learn what group to apply.
+	if v.supportsMountGroup && usage.FSGroup != nil {
+		mountOptions.VolumeMountGroup = strconv.FormatInt(*usage.FSGroup, 10)
+	}
+
+	return &csi.VolumeCapability{
+		AccessType:         accessType,
+		AccessMode:         accessMode,
+		VolumeMountOptions: mountOptions,
+	}, nil
+}
+
+// ensureStagingDir attempts to create a directory for use when staging a volume
+// and then validates that the path is not already a mount point for e.g an
+// existing volume stage.
+//
+// If the path is already a mount point but probing it fails with an errno
+// that indicates the mount is corrupted (e.g. its FUSE daemon died), the
+// stale mount is cleared so the caller re-stages rather than silently
+// reusing a broken mount.
+//
+// Returns whether the directory is a pre-existing (and usable) mountpoint,
+// the staging path, and any errors that occurred.
+func (v *volumeManager) ensureStagingDir(vol *structs.CSIVolume, usage *UsageOptions) (bool, string, error) {
+	stagingPath := v.stagingDirForVolume(vol, usage)
+
+	// Make the staging path, owned by the Nomad User
+	if err := os.MkdirAll(stagingPath, 0700); err != nil && !os.IsExist(err) {
+		return false, "", fmt.Errorf("failed to create staging directory for volume (%s): %v", vol.ID, err)
+	}
+
+	// Validate that it is not already a mount point
+	m := mount.New()
+	isNotMount, err := m.IsNotAMountPoint(stagingPath)
+	if err != nil {
+		return false, "", fmt.Errorf("mount point detection failed for volume (%s): %v", vol.ID, err)
+	}
+	if isNotMount {
+		return false, stagingPath, nil
+	}
+
+	if _, err := os.Stat(stagingPath); err != nil && mount.IsCorruptedMnt(err) {
+		v.logger.Warn("re-staging corrupted mount", "volume_id", vol.ID, "staging_path", stagingPath, "error", err)
+		v.emitEvent(&Event{Type: EventTypeMountCorrupted, VolumeID: vol.ID, Message: "re-staging corrupted mount: " + err.Error()})
+		if err := m.Unmount(stagingPath); err != nil {
+			return false, "", fmt.Errorf("failed to clear corrupted staging mount for volume (%s): %v", vol.ID, err)
+		}
+		return false, stagingPath, nil
+	}
+
+	return true, stagingPath, nil
+}
+
+// stageVolume prepares a volume for use by allocations. When a plugin exposes
+// the STAGE_UNSTAGE_VOLUME capability it MUST be called once-per-volume for a
+// given usage mode before the volume can be NodePublish-ed.
+func (v *volumeManager) stageVolume(ctx context.Context, vol *structs.CSIVolume, usage *UsageOptions) error {
+	logger := hclog.FromContext(ctx)
+	logger.Trace("Preparing volume staging environment")
+	existingMount, stagingPath, err := v.ensureStagingDir(vol, usage)
+	if err != nil {
+		return err
+	}
+	logger.Trace("Volume staging environment", "pre-existing_mount", existingMount, "staging_path", stagingPath)
+
+	if existingMount {
+		logger.Debug("re-using existing staging mount for volume", "staging_path", stagingPath)
+		return nil
+	}
+
+	capability, err := v.volumeCapability(vol, usage)
+	if err != nil {
+		return err
 	}
 
 	// We currently treat all explicit CSI NodeStageVolume errors (aside from timeouts, codes.ResourceExhausted, and codes.Unavailable)
@@ -139,41 +433,386 @@ func (v *volumeManager) stageVolume(ctx context.Context, vol *structs.CSIVolume)
 	// In the future, we can provide more useful error messages based on
 	// different types of error. For error documentation see:
 	// https://github.com/container-storage-interface/spec/blob/4731db0e0bc53238b93850f43ab05d9355df0fd9/spec.md#nodestagevolume-errors
-	return v.plugin.NodeStageVolume(ctx,
+	start := time.Now()
+	err = v.plugin.NodeStageVolume(ctx,
 		vol.ID,
-		nil, /* TODO: Get publishContext from Server */
+		vol.PublishContext,
 		stagingPath,
-		&csi.VolumeCapability{
-			AccessType:         accessType,
-			AccessMode:         accessMode,
-			VolumeMountOptions: &csi.VolumeMountOptions{
-				// GH-7007: Currently we have no way to provide these
-			},
-		},
+		capability,
 		grpc_retry.WithPerRetryTimeout(DefaultMountActionTimeout),
 		grpc_retry.WithMax(3),
 		grpc_retry.WithBackoff(grpc_retry.BackoffExponential(100*time.Millisecond)),
 	)
+	v.recordRPCMetrics("node_stage_volume", start, err)
+	v.emitRetryEventIfUnavailable("node_stage_volume", vol.ID, "", err)
+
+	return err
+}
+
+// ensureAllocDir is the per-alloc target analogue of ensureStagingDir: it
+// creates the per-alloc target directory and, if it is already mounted,
+// detects and clears a corrupted mount so the caller re-publishes rather
+// than reusing it.
+//
+// Returns whether the directory is a pre-existing (and usable) mountpoint,
+// the target path, and any errors that occurred.
+func (v *volumeManager) ensureAllocDir(vol *structs.CSIVolume, alloc *structs.Allocation) (bool, string, error) {
+	targetPath := v.allocDirForVolume(vol, alloc)
+
+	if err := os.MkdirAll(targetPath, 0700); err != nil && !os.IsExist(err) {
+		return false, "", fmt.Errorf("failed to create target dir for volume (%s): %v", vol.ID, err)
+	}
+
+	m := mount.New()
+	isNotMount, err := m.IsNotAMountPoint(targetPath)
+	if err != nil {
+		return false, "", fmt.Errorf("mount point detection failed for volume (%s): %v", vol.ID, err)
+	}
+	if isNotMount {
+		return false, targetPath, nil
+	}
+
+	if _, err := os.Stat(targetPath); err != nil && mount.IsCorruptedMnt(err) {
+		v.logger.Warn("re-staging corrupted mount", "volume_id", vol.ID, "target_path", targetPath, "error", err)
+		v.emitEvent(&Event{Type: EventTypeMountCorrupted, VolumeID: vol.ID, AllocID: alloc.ID, Message: "re-staging corrupted mount: " + err.Error()})
+		if err := m.Unmount(targetPath); err != nil {
+			return false, "", fmt.Errorf("failed to clear corrupted target mount for volume (%s): %v", vol.ID, err)
+		}
+		return false, targetPath, nil
+	}
+
+	return true, targetPath, nil
+}
+
+func (v *volumeManager) publishVolume(ctx context.Context, vol *structs.CSIVolume, alloc *structs.Allocation, usage *UsageOptions) (*MountInfo, error) {
+	logger := hclog.FromContext(ctx)
+
+	capability, err := v.volumeCapability(vol, usage)
+	if err != nil {
+		return nil, err
+	}
+
+	existingMount, targetPath, err := v.ensureAllocDir(vol, alloc)
+	if err != nil {
+		return nil, err
+	}
+
+	var stagingPath string
+	if v.requiresStaging {
+		stagingPath = v.stagingDirForVolume(vol, usage)
+	}
+
+	if existingMount {
+		logger.Debug("re-using existing target mount for volume", "target_path", targetPath)
+	} else {
+		logger.Trace("Publishing volume", "target_path", targetPath, "staging_path", stagingPath)
+
+		start := time.Now()
+		err = v.plugin.NodePublishVolume(ctx, &csi.NodePublishVolumeRequest{
+			VolumeID:          vol.ID,
+			PublishContext:    vol.PublishContext,
+			VolumeContext:     vol.Context,
+			StagingTargetPath: stagingPath,
+			TargetPath:        targetPath,
+			VolumeCapability:  capability,
+			Readonly:          usage.ReadOnly,
+		},
+			grpc_retry.WithPerRetryTimeout(DefaultMountActionTimeout),
+			grpc_retry.WithMax(3),
+			grpc_retry.WithBackoff(grpc_retry.BackoffExponential(100*time.Millisecond)),
+		)
+		v.recordRPCMetrics("node_publish_volume", start, err)
+		v.emitRetryEventIfUnavailable("node_publish_volume", vol.ID, alloc.ID, err)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Re-run these even when reusing an existing mount: the RPC may have
+	// succeeded on an earlier call but one of these local steps may not
+	// have (e.g. MountVolume returned an error from the chown below and a
+	// caller retry landed here via the existingMount fast-path), and both
+	// are cheap, idempotent, local operations.
+	if err := v.applyFSGroup(targetPath, usage); err != nil {
+		return nil, err
+	}
+
+	if usage.AttachmentMode == structs.CSIVolumeAttachmentModeBlockDevice && usage.ReadOnly {
+		if err := v.enforceBlockReadOnly(targetPath); err != nil {
+			return nil, err
+		}
+	}
+
+	v.emitEvent(&Event{Type: EventTypeMountSucceeded, VolumeID: vol.ID, AllocID: alloc.ID, Message: "mount succeeded"})
+
+	return &MountInfo{
+		Source:   targetPath,
+		IsDevice: usage.AttachmentMode == structs.CSIVolumeAttachmentModeBlockDevice,
+	}, nil
+}
+
+// applyFSGroup recursively chowns the mounted target to the requested
+// fsGroup and grants it rw (and, for directories, x) permission, so that an
+// allocation's tasks running as a different uid/gid than the CSI plugin can
+// use the volume. Skipped entirely for block volumes and whenever the
+// plugin already advertises the VOLUME_MOUNT_GROUP node capability, since
+// in that case the plugin applies the fsGroup itself during NodePublish.
+func (v *volumeManager) applyFSGroup(targetPath string, usage *UsageOptions) error {
+	if usage.FSGroup == nil || v.supportsMountGroup {
+		return nil
+	}
+	if usage.AttachmentMode == structs.CSIVolumeAttachmentModeBlockDevice {
+		return nil
+	}
+
+	gid := int(*usage.FSGroup)
+	return filepath.Walk(targetPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if err := os.Chown(path, -1, gid); err != nil {
+			return fmt.Errorf("failed to chown %s to fsGroup %d: %v", path, gid, err)
+		}
+
+		mode := info.Mode().Perm() | 0060 // g+rw
+		if info.IsDir() {
+			mode |= 0010 // g+x
+		}
+		return os.Chmod(path, mode)
+	})
+}
+
+// enforceBlockReadOnly bind-remounts a block volume's target path read-only.
+// Most CSI plugins do not themselves enforce the Readonly field set on
+// NodePublishVolumeRequest for block-mode volumes (the CSI spec only
+// requires it for filesystem mounts), so a claim with ReadOnly set would
+// otherwise still be writable through the published device file.
+func (v *volumeManager) enforceBlockReadOnly(targetPath string) error {
+	m := mount.New()
+	if err := m.Mount(targetPath, targetPath, "", []string{"bind", "remount", "ro"}); err != nil {
+		return fmt.Errorf("failed to remount block volume read-only at %s: %v", targetPath, err)
+	}
+	return nil
+}
+
+// emitRetryEventIfUnavailable reports an EventTypeMountRetried event when an
+// RPC returns Unavailable, the transient condition grpc_retry already
+// retries against internally: by the time the call returns to us here, the
+// interceptor has exhausted its retry budget, so this is the operator's
+// signal that the plugin was flaky/unreachable for the duration of the call.
+func (v *volumeManager) emitRetryEventIfUnavailable(rpc, volumeID, allocID string, err error) {
+	if err == nil || status.Code(err) != codes.Unavailable {
+		return
+	}
+	v.emitEvent(&Event{
+		Type:     EventTypeMountRetried,
+		VolumeID: volumeID,
+		AllocID:  allocID,
+		Message:  fmt.Sprintf("%s retries exhausted against Unavailable plugin: %v", rpc, err),
+	})
 }
 
 // MountVolume performs the steps required for using a given volume
 // configuration for the provided allocation.
-//
-// TODO: Validate remote volume attachment and implement.
-func (v *volumeManager) MountVolume(ctx context.Context, vol *structs.CSIVolume, alloc *structs.Allocation) (*MountInfo, error) {
-	logger := v.logger.With("volume_id", vol.ID)
+func (v *volumeManager) MountVolume(ctx context.Context, vol *structs.CSIVolume, alloc *structs.Allocation, usage *UsageOptions) (*MountInfo, error) {
+	logger := v.logger.With("volume_id", vol.ID, "alloc_id", alloc.ID)
 	ctx = hclog.WithContext(ctx, logger)
 
 	if v.requiresStaging {
-		err := v.stageVolume(ctx, vol)
+		err := v.stageVolume(ctx, vol, usage)
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	return nil, fmt.Errorf("Unimplemented")
+	mountInfo, err := v.publishVolume(ctx, vol, alloc, usage)
+	if err != nil {
+		return nil, err
+	}
+
+	key := v.usageTracker.uniqueKey(vol, usage)
+	v.usageTracker.Claim(alloc.ID, key)
+
+	if err := v.journalClaim(key, vol, usage, alloc.ID); err != nil {
+		return nil, fmt.Errorf("failed to persist volume state for volume (%s): %v", vol.ID, err)
+	}
+
+	return mountInfo, nil
+}
+
+// journalClaim records that allocID is now publishing the (volume, usage)
+// tuple identified by key, and fsyncs the update to the on-disk journal
+// before returning, so the claim is durable once MountVolume returns
+// successfully. MountVolume calls it after publishing, so a crash between
+// the two leaves a mount that the journal does not record.
+func (v *volumeManager) journalClaim(key string, vol *structs.CSIVolume, usage *UsageOptions, allocID string) error {
+	v.volumesMu.Lock()
+	defer v.volumesMu.Unlock()
+
+	entry, ok := v.volumes[key]
+	if !ok {
+		entry = &volumeJournalEntry{
+			VolumeID:       vol.ID,
+			StagingPath:    v.stagingDirForVolume(vol, usage),
+			Usage:          usage,
+			PublishContext: vol.PublishContext,
+		}
+		v.volumes[key] = entry
+	}
+
+	// A repeated MountVolume call for an alloc we've already recorded (e.g.
+	// a hook retry) must not append a second alloc ID, or Free/journalFree
+	// will never see the entry's AllocIDs reach zero and the mount leaks.
+	for _, id := range entry.AllocIDs {
+		if id == allocID {
+			return nil
+		}
+	}
+	entry.AllocIDs = append(entry.AllocIDs, allocID)
+
+	err := v.journal.persist(v.volumes)
+	v.updateVolumeGauges()
+	return err
+}
+
+// journalFree removes allocID from the (volume, usage) tuple identified by
+// key and fsyncs the update to the on-disk journal before returning. The
+// entry itself is deliberately NOT deleted once its AllocIDs list empties
+// out here: deleting it immediately, before NodeUnstageVolume has actually
+// been called/confirmed, would mean a failed unstage RPC or a client crash
+// during UnmountVolume leaves the staging mount orphaned with no journal
+// record to retry it from on the next restart. The entry is only removed
+// once journalForgetAfterUnstage confirms the unstage succeeded.
+func (v *volumeManager) journalFree(key, allocID string) error {
+	v.volumesMu.Lock()
+	defer v.volumesMu.Unlock()
+
+	entry, ok := v.volumes[key]
+	if !ok {
+		return nil
+	}
+
+	entry.removeAlloc(allocID)
+
+	err := v.journal.persist(v.volumes)
+	v.updateVolumeGauges()
+	return err
+}
+
+// journalForgetAfterUnstage removes the (now unstaged) entry for key from
+// the journal. It must only be called once NodeUnstageVolume has returned
+// successfully for that entry, or, for a plugin that does not require
+// staging, once the entry has no allocations left.
+func (v *volumeManager) journalForgetAfterUnstage(key string) error {
+	v.volumesMu.Lock()
+	defer v.volumesMu.Unlock()
+
+	delete(v.volumes, key)
+
+	err := v.journal.persist(v.volumes)
+	v.updateVolumeGauges()
+	return err
+}
+
+// unstageAndForget calls NodeUnstageVolume directly from the entry's
+// recorded staging path and volume ID (used during journal restore, where
+// we no longer have the original *structs.CSIVolume/UsageOptions, only what
+// was persisted), then removes the entry from the journal on success so a
+// future restart does not try to unstage it again.
+func (v *volumeManager) unstageAndForget(key string, entry *volumeJournalEntry) error {
+	start := time.Now()
+	err := v.plugin.NodeUnstageVolume(context.Background(),
+		entry.VolumeID,
+		entry.StagingPath,
+		grpc_retry.WithPerRetryTimeout(DefaultMountActionTimeout),
+		grpc_retry.WithMax(3),
+		grpc_retry.WithBackoff(grpc_retry.BackoffExponential(100*time.Millisecond)),
+	)
+	v.recordRPCMetrics("node_unstage_volume", start, err)
+	if err != nil {
+		return err
+	}
+
+	v.emitEvent(&Event{Type: EventTypeUnmountSucceeded, VolumeID: entry.VolumeID, Message: "unstage succeeded"})
+
+	v.volumesMu.Lock()
+	delete(v.volumes, key)
+	v.volumesMu.Unlock()
+
+	return nil
+}
+
+func (v *volumeManager) unpublishVolume(ctx context.Context, vol *structs.CSIVolume, alloc *structs.Allocation, usage *UsageOptions) error {
+	targetPath := v.allocDirForVolume(vol, alloc)
+
+	start := time.Now()
+	err := v.plugin.NodeUnpublishVolume(ctx, vol.ID, targetPath,
+		grpc_retry.WithPerRetryTimeout(DefaultMountActionTimeout),
+		grpc_retry.WithMax(3),
+		grpc_retry.WithBackoff(grpc_retry.BackoffExponential(100*time.Millisecond)),
+	)
+	v.recordRPCMetrics("node_unpublish_volume", start, err)
+	if err != nil {
+		return err
+	}
+
+	v.emitEvent(&Event{Type: EventTypeUnmountSucceeded, VolumeID: vol.ID, AllocID: alloc.ID, Message: "unpublish succeeded"})
+
+	return os.RemoveAll(targetPath)
+}
+
+func (v *volumeManager) unstageVolume(ctx context.Context, vol *structs.CSIVolume, usage *UsageOptions) error {
+	logger := hclog.FromContext(ctx)
+	logger.Trace("Unstaging volume")
+	stagingPath := v.stagingDirForVolume(vol, usage)
+
+	start := time.Now()
+	err := v.plugin.NodeUnstageVolume(ctx,
+		vol.ID,
+		stagingPath,
+		grpc_retry.WithPerRetryTimeout(DefaultMountActionTimeout),
+		grpc_retry.WithMax(3),
+		grpc_retry.WithBackoff(grpc_retry.BackoffExponential(100*time.Millisecond)),
+	)
+	v.recordRPCMetrics("node_unstage_volume", start, err)
+	if err == nil {
+		v.emitEvent(&Event{Type: EventTypeUnmountSucceeded, VolumeID: vol.ID, Message: "unstage succeeded"})
+	}
+
+	return err
 }
 
-func (v *volumeManager) UnmountVolume(ctx context.Context, vol *structs.CSIVolume, alloc *structs.Allocation) error {
-	return fmt.Errorf("Unimplemented")
-}
\ No newline at end of file
+// UnmountVolume is the inverse operation of MountVolume and is used to
+// cleanup the mount point created by MountVolume.
+//
+// It unpublishes the volume from the given allocation's target path and
+// releases that allocation's claim. The staging mount is unstaged only once
+// the last allocation using the (volume, usage) tuple has released it.
+func (v *volumeManager) UnmountVolume(ctx context.Context, vol *structs.CSIVolume, alloc *structs.Allocation, usage *UsageOptions) error {
+	logger := v.logger.With("volume_id", vol.ID, "alloc_id", alloc.ID)
+	ctx = hclog.WithContext(ctx, logger)
+
+	err := v.unpublishVolume(ctx, vol, alloc, usage)
+	if err != nil {
+		return err
+	}
+
+	key := v.usageTracker.uniqueKey(vol, usage)
+	lastUser := v.usageTracker.Free(alloc.ID, key)
+
+	if err := v.journalFree(key, alloc.ID); err != nil {
+		return fmt.Errorf("failed to persist volume state for volume (%s): %v", vol.ID, err)
+	}
+
+	if v.requiresStaging && lastUser {
+		if err := v.unstageVolume(ctx, vol, usage); err != nil {
+			return err
+		}
+		if err := v.journalForgetAfterUnstage(key); err != nil {
+			return fmt.Errorf("failed to persist volume state for volume (%s): %v", vol.ID, err)
+		}
+	}
+
+	return nil
+}
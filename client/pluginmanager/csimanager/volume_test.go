@@ -0,0 +1,81 @@
+package csimanager
+
+import (
+	"testing"
+
+	"github.com/hashicorp/nomad/nomad/structs"
+)
+
+// TestVolumeCapability_MountOptionsPrecedence exercises the merge precedence
+// volumeCapability relies on: a volume's mount_options set at registration
+// time are the defaults, and the jobspec volume stanza's usage.MountOptions
+// override them field-by-field.
+func TestVolumeCapability_MountOptionsPrecedence(t *testing.T) {
+	cases := []struct {
+		name           string
+		registered     *structs.CSIMountOptions
+		claimed        *CSIMountOptions
+		wantFSType     string
+		wantMountFlags []string
+	}{
+		{
+			name:           "no overrides uses the registered defaults",
+			registered:     &structs.CSIMountOptions{FSType: "ext4", MountFlags: []string{"noatime"}},
+			claimed:        nil,
+			wantFSType:     "ext4",
+			wantMountFlags: []string{"noatime"},
+		},
+		{
+			name:           "jobspec override replaces the registered defaults",
+			registered:     &structs.CSIMountOptions{FSType: "ext4", MountFlags: []string{"noatime"}},
+			claimed:        &CSIMountOptions{FSType: "nfs4", MountFlags: []string{"nfsvers=4.1"}},
+			wantFSType:     "nfs4",
+			wantMountFlags: []string{"nfsvers=4.1"},
+		},
+		{
+			name:           "claim has no fs_type, registered default is kept",
+			registered:     &structs.CSIMountOptions{FSType: "ext4"},
+			claimed:        &CSIMountOptions{MountFlags: []string{"nfsvers=4.1"}},
+			wantFSType:     "ext4",
+			wantMountFlags: []string{"nfsvers=4.1"},
+		},
+		{
+			name:           "no registration default, only the claim applies",
+			registered:     nil,
+			claimed:        &CSIMountOptions{FSType: "nfs4", MountFlags: []string{"nfsvers=4.1"}},
+			wantFSType:     "nfs4",
+			wantMountFlags: []string{"nfsvers=4.1"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			v := &volumeManager{}
+			vol := &structs.CSIVolume{ID: "vol-1", MountOptions: tc.registered}
+			usage := &UsageOptions{
+				AttachmentMode: structs.CSIVolumeAttachmentModeFilesystem,
+				AccessMode:     structs.CSIVolumeAccessModeSingleNodeWriter,
+				MountOptions:   tc.claimed,
+			}
+
+			capability, err := v.volumeCapability(vol, usage)
+			if err != nil {
+				t.Fatalf("volumeCapability returned an error: %v", err)
+			}
+
+			if capability.VolumeMountOptions.FSType != tc.wantFSType {
+				t.Fatalf("expected FSType %q, got %q", tc.wantFSType, capability.VolumeMountOptions.FSType)
+			}
+
+			got := capability.VolumeMountOptions.MountFlags
+			if len(got) != len(tc.wantMountFlags) {
+				t.Fatalf("expected MountFlags %v, got %v", tc.wantMountFlags, got)
+			}
+			for i := range got {
+				if got[i] != tc.wantMountFlags[i] {
+					t.Fatalf("expected MountFlags %v, got %v", tc.wantMountFlags, got)
+				}
+			}
+		})
+	}
+}
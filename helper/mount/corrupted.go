@@ -0,0 +1,43 @@
+// +build linux
+
+package mount
+
+import (
+	"os"
+	"syscall"
+)
+
+// IsCorruptedMnt returns true if the given error (as returned by a stat of
+// a mount point) indicates that the mount is present but its underlying
+// transport has gone away, e.g. a FUSE-based CSI plugin whose userspace
+// process died. Mature CSI node plugins detect this rather than silently
+// reusing (and getting I/O errors from) a broken mount.
+func IsCorruptedMnt(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var underlyingError error
+	switch pe := err.(type) {
+	case *os.PathError:
+		underlyingError = pe.Err
+	case *os.LinkError:
+		underlyingError = pe.Err
+	case *os.SyscallError:
+		underlyingError = pe.Err
+	default:
+		underlyingError = err
+	}
+
+	errno, ok := underlyingError.(syscall.Errno)
+	if !ok {
+		return false
+	}
+
+	switch errno {
+	case syscall.ENOTCONN, syscall.ESTALE, syscall.EIO, syscall.EACCES:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,66 @@
+// +build linux
+
+package mount
+
+import (
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestIsCorruptedMnt(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{
+			name: "nil error",
+			err:  nil,
+			want: false,
+		},
+		{
+			name: "PathError wrapping ENOTCONN",
+			err:  &os.PathError{Op: "stat", Path: "/mnt/vol", Err: syscall.ENOTCONN},
+			want: true,
+		},
+		{
+			name: "LinkError wrapping ESTALE",
+			err:  &os.LinkError{Op: "rename", Old: "/mnt/vol/a", New: "/mnt/vol/b", Err: syscall.ESTALE},
+			want: true,
+		},
+		{
+			name: "SyscallError wrapping EIO",
+			err:  os.NewSyscallError("read", syscall.EIO),
+			want: true,
+		},
+		{
+			name: "PathError wrapping EACCES",
+			err:  &os.PathError{Op: "stat", Path: "/mnt/vol", Err: syscall.EACCES},
+			want: true,
+		},
+		{
+			name: "PathError wrapping a non-matching errno",
+			err:  &os.PathError{Op: "stat", Path: "/mnt/vol", Err: syscall.ENOENT},
+			want: false,
+		},
+		{
+			name: "bare non-matching errno",
+			err:  syscall.ENOENT,
+			want: false,
+		},
+		{
+			name: "non-errno error",
+			err:  os.ErrInvalid,
+			want: false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := IsCorruptedMnt(tc.err); got != tc.want {
+				t.Fatalf("IsCorruptedMnt(%v) = %v, want %v", tc.err, got, tc.want)
+			}
+		})
+	}
+}
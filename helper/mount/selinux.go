@@ -0,0 +1,22 @@
+// +build linux
+
+package mount
+
+import (
+	"io/ioutil"
+	"strings"
+)
+
+const selinuxEnforceFile = "/sys/fs/selinux/enforce"
+
+// SELinuxEnabled reports whether SELinux is enabled and enforcing on this
+// host, by reading /sys/fs/selinux/enforce. A host without SELinux, or with
+// it in permissive mode, does not need its CSI mounts relabeled.
+func SELinuxEnabled() bool {
+	raw, err := ioutil.ReadFile(selinuxEnforceFile)
+	if err != nil {
+		return false
+	}
+
+	return strings.TrimSpace(string(raw)) == "1"
+}